@@ -1,33 +1,44 @@
 package service
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"regexp"
 	"time"
 
 	"github.com/Financial-Times/kafka-client-go/kafka"
+	"github.com/Financial-Times/pac-annotations-mapper/uuidutils"
 	"github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
+	"github.com/xeipuuv/gojsonschema"
 )
 
 const messageTimestampDateFormat = "2006-01-02T15:04:05.000Z"
 
-var predicates = map[string]string{
-	"http://www.ft.com/ontology/classification/isClassifiedBy":"isClassifiedBy",
-	"http://www.ft.com/ontology/annotation/hasAuthor":"hasAuthor",
-	"http://www.ft.com/ontology/annotation/hasContributor":"hasContributor",
-	"http://www.ft.com/ontology/annotation/about":"about",
-	"http://www.ft.com/ontology/annotation/hasDisplayTag":"hasDisplayTag",
-	"http://www.ft.com/ontology/annotation/mentions":"mentions",
-}
-
 type AnnotationMapperService struct {
-	whitelist       *regexp.Regexp
-	messageProducer kafka.Producer
+	whitelist          *regexp.Regexp
+	schema             *gojsonschema.Schema
+	predicates         *PredicateRegistry
+	passthroughHeaders []string
+	strictUUID         bool
+	messageProducer    kafka.Producer
+	deadLetterProducer kafka.Producer
 }
 
-func NewAnnotationMapperService(whitelist *regexp.Regexp, messageProducer kafka.Producer) *AnnotationMapperService {
-	return &AnnotationMapperService{whitelist, messageProducer}
+// NewAnnotationMapperService creates a mapper that validates every incoming
+// message against schema (see LoadSchema) before mapping it, using predicates
+// (see LoadPredicateRegistry) to decide which annotations to map.
+// passthroughHeaders lists additional PAC event headers, beyond the fixed
+// set this mapper already forwards, to copy onto the outgoing
+// concept-annotation message unchanged. strictUUID controls what happens
+// when a ConceptId doesn't carry a valid UUID: when true the whole message
+// is rejected, when false only that annotation is skipped.
+// deadLetterProducer is optional: pass nil to drop messages that fail
+// validation, or a producer for a configured dead-letter topic to forward
+// them instead.
+func NewAnnotationMapperService(whitelist *regexp.Regexp, schema *gojsonschema.Schema, predicates *PredicateRegistry, passthroughHeaders []string, strictUUID bool, messageProducer kafka.Producer, deadLetterProducer kafka.Producer) *AnnotationMapperService {
+	return &AnnotationMapperService{whitelist, schema, predicates, passthroughHeaders, strictUUID, messageProducer, deadLetterProducer}
 }
 
 func (mapper *AnnotationMapperService) HandleMessage(msg kafka.FTMessage) error {
@@ -40,6 +51,12 @@ func (mapper *AnnotationMapperService) HandleMessage(msg kafka.FTMessage) error
 		return nil
 	}
 
+	if err := validateMessage(mapper.schema, []byte(msg.Body)); err != nil {
+		requestLog.WithError(err).Error("Message failed schema validation")
+		mapper.forwardToDeadLetterQueue(msg, requestLog)
+		return nil
+	}
+
 	var metadataPublishEvent PacMetadataPublishEvent
 	err := json.Unmarshal([]byte(msg.Body), &metadataPublishEvent)
 	if err != nil {
@@ -48,15 +65,31 @@ func (mapper *AnnotationMapperService) HandleMessage(msg kafka.FTMessage) error
 	}
 
 	requestLog = requestLog.WithField("uuid", metadataPublishEvent.UUID)
+
+	// The schema only checks that "uuid" has the 8-4-4-4-12 hex layout;
+	// IsValid additionally requires an RFC 4122 version nibble (1-5) and
+	// variant bits, so a UUID-shaped value that passed validateMessage
+	// above can still be rejected here.
+	if !uuidutils.IsValid(metadataPublishEvent.UUID) {
+		requestLog.Error("Rejecting message: content UUID is not a valid UUID")
+		rejectedContentUUIDs.Inc()
+		mapper.forwardToDeadLetterQueue(msg, requestLog)
+		return nil
+	}
+
 	requestLog.Info("Processing metadata publish event")
 
 	annotations := []annotation{}
 	for _, value := range metadataPublishEvent.Annotations {
-		ann := mapper.buildAnnotation(value)
+		ann, skipReason, err := mapper.buildAnnotation(value, systemCode)
+		if err != nil {
+			requestLog.WithError(err).WithField("metadata", value).Error("Rejecting message due to an invalid annotation")
+			return err
+		}
 		if ann != nil {
 			annotations = append(annotations, *ann)
-		} else {
-			requestLog.WithField("metadata", value).Warn("metadata for an unsupported predicate was not mapped")
+		} else if skipReason != "" {
+			requestLog.WithField("metadata", value).Warn(skipReason)
 		}
 	}
 
@@ -68,7 +101,7 @@ func (mapper *AnnotationMapperService) HandleMessage(msg kafka.FTMessage) error
 		return err
 	}
 
-	var headers = buildConceptAnnotationsHeader(msg.Headers)
+	var headers = buildConceptAnnotationsHeader(msg.Headers, marshalledAnnotations, mapper.passthroughHeaders)
 	message := kafka.FTMessage{Headers: headers, Body: string(marshalledAnnotations)}
 	err = mapper.messageProducer.SendMessage(message)
 	if err != nil {
@@ -79,24 +112,108 @@ func (mapper *AnnotationMapperService) HandleMessage(msg kafka.FTMessage) error
 	return nil
 }
 
-func (mapper *AnnotationMapperService) buildAnnotation(metadata PacMetadataAnnotation) *annotation {
-	var ann *annotation
+// forwardToDeadLetterQueue publishes a message that was rejected before
+// mapping (it failed schema validation or its content UUID is not a valid
+// UUID) to the configured dead-letter topic, if one is set, so that bad producers
+// are visible without blocking the main pipeline.
+func (mapper *AnnotationMapperService) forwardToDeadLetterQueue(msg kafka.FTMessage, requestLog *log.Entry) {
+	if mapper.deadLetterProducer == nil {
+		return
+	}
+	if err := mapper.deadLetterProducer.SendMessage(msg); err != nil {
+		requestLog.WithError(err).Error("Error forwarding invalid message to the dead-letter queue")
+	}
+}
+
+// buildAnnotation maps a single PAC annotation using the configured
+// PredicateRegistry, after checking that ConceptId carries a valid concept
+// UUID. It returns a nil annotation (with no error) when the annotation
+// should simply be skipped, along with a reason describing why for the
+// caller to log (empty when an unknown predicate is skipped silently under
+// PolicySkip); it returns an error when the annotation should instead
+// fail the whole message: either the registry's unsupported-predicate
+// policy is PolicyFail, or the concept UUID is invalid and strictUUID is
+// set.
+func (mapper *AnnotationMapperService) buildAnnotation(metadata PacMetadataAnnotation, originSystemID string) (ann *annotation, skipReason string, err error) {
+	if _, ok := conceptUUID(metadata.ConceptId); !ok {
+		rejectedConceptUUIDs.Inc()
+		if mapper.strictUUID {
+			return nil, "", errInvalidUUID(metadata.ConceptId)
+		}
+		return nil, "concept ID did not carry a valid UUID", nil
+	}
+
+	predicate, ok, reason, err := mapper.predicates.Lookup(metadata.Predicate, originSystemID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !ok {
+		return nil, reason, nil
+	}
+
+	provenance := firstUsableProvenance(metadata.Provenances)
+	if mapper.predicates.RequiresProvenance(metadata.Predicate) && provenance == nil {
+		return nil, "predicate requires provenance but none was supplied", nil
+	}
 
-	if predicate, found := predicates[metadata.Predicate]; found {
-		thing := thing{ID: metadata.ConceptId, Predicate: predicate}
-		ann = &annotation{Thing: thing}
+	thing := thing{ID: metadata.ConceptId, Predicate: predicate}
+	ann = &annotation{Thing: thing}
+	if provenance != nil {
+		ann.Provenances = []Provenance{*provenance}
 	}
 
-	return ann
+	return ann, "", nil
+}
+
+// firstUsableProvenance returns the first provenance that carries an agent
+// role or at least one score, ignoring any empty entries that precede it.
+// PAC events may supply several provenances for a single annotation; the
+// first non-empty one wins.
+func firstUsableProvenance(provenances []Provenance) *Provenance {
+	for _, provenance := range provenances {
+		if provenance.AgentRole != "" || len(provenance.Scores) > 0 {
+			return &provenance
+		}
+	}
+	return nil
 }
 
-func buildConceptAnnotationsHeader(publishEventHeaders map[string]string) map[string]string {
-	return map[string]string{
+// buildConceptAnnotationsHeader builds the headers for the outgoing
+// concept-annotation message. Document-Hash is a stable hash of body so the
+// downstream writer can perform a 3-way compare against any
+// Previous-Document-Hash it forwarded on a later replace; passthroughHeaders
+// carries through any additional publishEventHeaders an operator has
+// configured, beyond the fixed set below.
+func buildConceptAnnotationsHeader(publishEventHeaders map[string]string, body []byte, passthroughHeaders []string) map[string]string {
+	headers := map[string]string{
 		"Message-Id":        uuid.NewV4().String(),
 		"Message-Type":      "concept-annotation",
 		"Content-Type":      publishEventHeaders["Content-Type"],
 		"X-Request-Id":      publishEventHeaders["X-Request-Id"],
 		"Origin-System-Id":  publishEventHeaders["Origin-System-Id"],
 		"Message-Timestamp": time.Now().Format(messageTimestampDateFormat),
+		"Document-Hash":     documentHash(body),
 	}
+
+	if previousHash, found := publishEventHeaders["Previous-Document-Hash"]; found {
+		headers["Previous-Document-Hash"] = previousHash
+	}
+
+	for _, name := range passthroughHeaders {
+		if _, managed := headers[name]; managed {
+			continue
+		}
+		if value, found := publishEventHeaders[name]; found {
+			headers[name] = value
+		}
+	}
+
+	return headers
+}
+
+// documentHash returns a stable content hash of a marshalled
+// ConceptAnnotations payload, used as the Document-Hash header.
+func documentHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
 }
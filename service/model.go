@@ -0,0 +1,50 @@
+package service
+
+// PacMetadataPublishEvent is the payload published by PAC when a piece of
+// content's metadata (annotations) has changed.
+type PacMetadataPublishEvent struct {
+	UUID        string                  `json:"uuid"`
+	Annotations []PacMetadataAnnotation `json:"annotations"`
+}
+
+// PacMetadataAnnotation is a single annotation as it is expressed by PAC,
+// linking a piece of content to a concept via a predicate.
+type PacMetadataAnnotation struct {
+	Predicate   string       `json:"predicate"`
+	ConceptId   string       `json:"id"`
+	Provenances []Provenance `json:"provenances,omitempty"`
+}
+
+// Provenance records how an annotation came to exist, e.g. which agent
+// (human or automated classifier) produced it, when, and with what
+// confidence/relevance scores.
+type Provenance struct {
+	Scores    []Score `json:"scores,omitempty"`
+	AgentRole string  `json:"agentRole,omitempty"`
+	AtTime    string  `json:"atTime,omitempty"`
+}
+
+// Score is a single named score attached to a Provenance, e.g. relevance or
+// confidence.
+type Score struct {
+	ScoringSystem string  `json:"scoringSystem,omitempty"`
+	Value         float64 `json:"value,omitempty"`
+}
+
+// ConceptAnnotations is the message this mapper publishes: a piece of
+// content's UUID together with the annotations mapped from PAC's format
+// into the shape downstream concept-annotation consumers expect.
+type ConceptAnnotations struct {
+	UUID        string       `json:"uuid"`
+	Annotations []annotation `json:"annotations"`
+}
+
+type annotation struct {
+	Thing       thing        `json:"thing"`
+	Provenances []Provenance `json:"provenances,omitempty"`
+}
+
+type thing struct {
+	ID        string `json:"id"`
+	Predicate string `json:"predicate"`
+}
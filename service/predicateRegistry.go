@@ -0,0 +1,123 @@
+package service
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// UnsupportedPredicateErr is returned by PredicateRegistry.Lookup when a
+// predicate is absent from the registry and the policy is PolicyFail. It
+// mirrors the UnsupportedPredicateErr used by the concept-annotation
+// Neo4j writer for the same condition.
+type UnsupportedPredicateErr struct {
+	Predicate string
+}
+
+func (e UnsupportedPredicateErr) Error() string {
+	return fmt.Sprintf("predicate %s is not supported", e.Predicate)
+}
+
+// UnsupportedPredicatePolicy controls what happens when an annotation's
+// predicate isn't registered.
+type UnsupportedPredicatePolicy string
+
+const (
+	PolicySkip UnsupportedPredicatePolicy = "skip"
+	PolicyWarn UnsupportedPredicatePolicy = "warn"
+	PolicyFail UnsupportedPredicatePolicy = "fail"
+)
+
+// PredicateDefinition describes how a single ontology predicate should be
+// mapped, and any constraints on where an annotation using it may come from.
+type PredicateDefinition struct {
+	URI               string   `yaml:"uri"`
+	Name              string   `yaml:"name"`
+	RequireProvenance bool     `yaml:"requireProvenance"`
+	AllowedSystems    []string `yaml:"allowedSystems"`
+}
+
+// PredicateRegistry holds the set of predicates this mapper accepts. It
+// replaces the previous hard-coded predicates map so operators can register
+// a new predicate, or restrict one to particular Origin-System-Ids, via
+// config rather than a code change and redeploy.
+type PredicateRegistry struct {
+	definitions map[string]PredicateDefinition
+	policy      UnsupportedPredicatePolicy
+}
+
+// NewPredicateRegistry builds a registry from an already-loaded list of
+// definitions and the policy to apply to predicates outside that list.
+func NewPredicateRegistry(definitions []PredicateDefinition, policy UnsupportedPredicatePolicy) *PredicateRegistry {
+	byURI := make(map[string]PredicateDefinition, len(definitions))
+	for _, definition := range definitions {
+		byURI[definition.URI] = definition
+	}
+	return &PredicateRegistry{definitions: byURI, policy: policy}
+}
+
+// LoadPredicateRegistry reads a YAML predicate configuration file, e.g.:
+//
+//	- uri: http://www.ft.com/ontology/annotation/about
+//	  name: about
+//	- uri: http://www.ft.com/ontology/annotation/hasBrand
+//	  name: hasBrand
+//	  requireProvenance: true
+//	  allowedSystems: ["http://api.ft.com/system/pac-brand-classifier"]
+func LoadPredicateRegistry(path string, policy UnsupportedPredicatePolicy) (*PredicateRegistry, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var definitions []PredicateDefinition
+	if err := yaml.Unmarshal(raw, &definitions); err != nil {
+		return nil, err
+	}
+
+	return NewPredicateRegistry(definitions, policy), nil
+}
+
+// Lookup resolves the short predicate name to use downstream for a given
+// predicate URI and Origin-System-Id. ok is false when the annotation
+// should not be mapped. reason explains why, for the caller to log with
+// its own transaction-scoped logger: it's empty when the predicate is
+// simply unknown and the policy is PolicySkip, so the caller can stay
+// silent, and set whenever the policy is PolicyWarn or the predicate is
+// known but not whitelisted for originSystemID. err is only non-nil when
+// the predicate is unknown and the policy is PolicyFail.
+func (r *PredicateRegistry) Lookup(predicateURI, originSystemID string) (name string, ok bool, reason string, err error) {
+	definition, found := r.definitions[predicateURI]
+	if !found {
+		switch r.policy {
+		case PolicyFail:
+			return "", false, "", UnsupportedPredicateErr{Predicate: predicateURI}
+		case PolicyWarn:
+			return "", false, "unsupported predicate was not mapped", nil
+		default:
+			return "", false, "", nil
+		}
+	}
+
+	if len(definition.AllowedSystems) > 0 && !contains(definition.AllowedSystems, originSystemID) {
+		return "", false, "predicate is not whitelisted for this Origin-System-Id", nil
+	}
+
+	return definition.Name, true, "", nil
+}
+
+// RequiresProvenance reports whether the given predicate must carry
+// provenance in order to be mapped.
+func (r *PredicateRegistry) RequiresProvenance(predicateURI string) bool {
+	return r.definitions[predicateURI].RequireProvenance
+}
+
+func contains(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
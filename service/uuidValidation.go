@@ -0,0 +1,45 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/Financial-Times/pac-annotations-mapper/uuidutils"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var rejectedConceptUUIDs = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "pac_annotations_mapper",
+	Name:      "rejected_concept_uuids_total",
+	Help:      "Number of annotations rejected because the concept ID did not carry a valid UUID.",
+})
+
+var rejectedContentUUIDs = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "pac_annotations_mapper",
+	Name:      "rejected_content_uuids_total",
+	Help:      "Number of messages rejected because the content UUID was not a valid UUID.",
+})
+
+func init() {
+	prometheus.MustRegister(rejectedConceptUUIDs)
+	prometheus.MustRegister(rejectedContentUUIDs)
+}
+
+// errInvalidUUID is returned when an annotation's concept ID fails RFC 4122
+// validation and strictUUID means that individual bad annotation escalates
+// to a message-level failure.
+type errInvalidUUID string
+
+func (e errInvalidUUID) Error() string {
+	return fmt.Sprintf("invalid UUID: %s", string(e))
+}
+
+// conceptUUID extracts and validates the UUID embedded in a concept URI,
+// e.g. http://api.ft.com/things/<uuid>. ok is false when the URI has no
+// trailing UUID or that UUID isn't RFC 4122-valid.
+func conceptUUID(conceptID string) (id string, ok bool) {
+	id, found := uuidutils.ExtractFromConceptURI(conceptID)
+	if !found || !uuidutils.IsValid(id) {
+		return "", false
+	}
+	return id, true
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+var invalidMessages = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "pac_annotations_mapper",
+	Name:      "schema_validation_failures_total",
+	Help:      "Number of PAC metadata publish events rejected by JSON Schema validation.",
+})
+
+func init() {
+	prometheus.MustRegister(invalidMessages)
+}
+
+// validateMessage checks the raw PAC metadata publish event body against
+// the compiled schema. It returns nil when the document is valid, and a
+// single error describing every failing JSON Pointer otherwise.
+func validateMessage(schema *gojsonschema.Schema, body []byte) error {
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(body))
+	if err != nil {
+		invalidMessages.Inc()
+		return err
+	}
+	if result.Valid() {
+		return nil
+	}
+
+	pointers := make([]string, 0, len(result.Errors()))
+	for _, resultErr := range result.Errors() {
+		pointers = append(pointers, resultErr.Context().JSONPointer()+": "+resultErr.Description())
+	}
+	invalidMessages.Inc()
+	return errValidation(strings.Join(pointers, "; "))
+}
+
+type errValidation string
+
+func (e errValidation) Error() string {
+	return "message failed schema validation: " + string(e)
+}
+
+// LoadSchema compiles the JSON Schema at the given path (e.g. the
+// schemas/pacMetadataPublishEvent.json file shipped alongside this
+// service) for use with NewAnnotationMapperService.
+func LoadSchema(path string) (*gojsonschema.Schema, error) {
+	return gojsonschema.NewSchema(gojsonschema.NewReferenceLoader("file://" + path))
+}
@@ -0,0 +1,38 @@
+// Package uuidutils provides helpers for extracting and validating the
+// UUIDs found in PAC metadata publish events: the content UUID, and the
+// concept UUIDs embedded in concept URIs such as
+// http://api.ft.com/things/<uuid>.
+package uuidutils
+
+import "regexp"
+
+// uuidPattern matches the generic 8-4-4-4-12 hex layout, used to find the
+// trailing UUID segment of a concept URI before it's validated.
+var uuidPattern = `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`
+
+// rfc4122Pattern additionally constrains the version nibble to 1-5 and the
+// variant bits to the 10xx form RFC 4122 requires, rejecting hex strings
+// that merely look UUID-shaped.
+var rfc4122Pattern = `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[1-5][0-9a-fA-F]{3}-[89abAB][0-9a-fA-F]{3}-[0-9a-fA-F]{12}`
+
+var conceptURIRegexp = regexp.MustCompile(`(` + uuidPattern + `)$`)
+
+var uuidRegexp = regexp.MustCompile(`^` + rfc4122Pattern + `$`)
+
+// ExtractFromConceptURI pulls the trailing UUID segment off a concept URI,
+// e.g. http://api.ft.com/things/<uuid>. It returns false if the URI has no
+// trailing UUID. The result still needs IsValid to confirm it's RFC
+// 4122-compliant, not just UUID-shaped.
+func ExtractFromConceptURI(conceptURI string) (string, bool) {
+	match := conceptURIRegexp.FindStringSubmatch(conceptURI)
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
+// IsValid reports whether id is a well-formed RFC 4122 UUID: 8-4-4-4-12 hex
+// with a version nibble of 1-5 and the RFC 4122 variant bits set.
+func IsValid(id string) bool {
+	return uuidRegexp.MatchString(id)
+}